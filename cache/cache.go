@@ -0,0 +1,351 @@
+// Package cache implements a content-addressed on-disk cache, modeled on
+// the design of the Go toolchain's build cache (cmd/go/internal/cache):
+// entries are keyed by a 32-byte action ID and store their output under a
+// 32-byte output ID (the SHA-256 of the output bytes), sharded across
+// "xx/" subdirectories so no single directory grows unbounded.
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/albertocavalcante/utils/lockedfile"
+)
+
+// idSize is the width, in bytes, of both action and output IDs.
+const idSize = sha256.Size
+
+// ActionID identifies a unit of cacheable work, such as a hash of its
+// inputs. Callers compute ActionIDs themselves, typically by hashing the
+// parameters of the work being cached.
+type ActionID [idSize]byte
+
+// OutputID identifies the cached bytes produced for an ActionID: the
+// SHA-256 of the output content.
+type OutputID [idSize]byte
+
+// ErrNotExist is returned by Get and GetFile when no entry exists for an
+// ActionID, or when the underlying files have been removed or corrupted.
+var ErrNotExist = errors.New("cache: entry not found")
+
+// Entry describes a cached action's recorded output.
+type Entry struct {
+	OutputID OutputID
+	Size     int64
+	Time     time.Time
+}
+
+// DefaultTTL is the age after which Trim evicts an entry that hasn't been
+// touched, matching the default used by the Go toolchain's build cache.
+const DefaultTTL = 5 * 24 * time.Hour
+
+// touchWindow is the minimum interval between mtime updates for the same
+// entry; Get skips the update if the entry was touched more recently than
+// this, so that LRU bookkeeping stays cheap even under heavy read load.
+const touchWindow = time.Hour
+
+// Cache is a directory of content-addressed entries. The zero value is not
+// usable; construct one with Open or Default.
+type Cache struct {
+	dir      string
+	ttl      time.Duration
+	disabled bool
+
+	mu      sync.Mutex
+	touched map[ActionID]time.Time
+}
+
+// Open opens (creating if necessary) a cache rooted at dir, including its
+// 256 two-hex-digit shard subdirectories. TTL defaults to DefaultTTL; call
+// SetTTL after Open to override it.
+func Open(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("cache: opening %q: %w", dir, err)
+	}
+	for i := 0; i < 256; i++ {
+		shard := filepath.Join(dir, fmt.Sprintf("%02x", i))
+		if err := os.MkdirAll(shard, 0o755); err != nil {
+			return nil, fmt.Errorf("cache: opening %q: %w", dir, err)
+		}
+	}
+	return &Cache{
+		dir:     dir,
+		ttl:     DefaultTTL,
+		touched: make(map[ActionID]time.Time),
+	}, nil
+}
+
+// SetTTL overrides the age after which Trim evicts untouched entries.
+func (c *Cache) SetTTL(d time.Duration) {
+	c.ttl = d
+}
+
+// Get looks up the entry for id. It returns ErrNotExist if no entry exists,
+// or if the recorded output is missing or corrupt.
+func (c *Cache) Get(id ActionID) (Entry, error) {
+	if c.disabled {
+		return Entry{}, ErrNotExist
+	}
+
+	actionFile := c.fileName(id[:], "a")
+	data, err := lockedfile.Read(actionFile)
+	if err != nil {
+		return Entry{}, ErrNotExist
+	}
+	entry, err := parseEntry(data)
+	if err != nil {
+		return Entry{}, ErrNotExist
+	}
+
+	dataFile := c.fileName(entry.OutputID[:], "d")
+	if info, err := os.Stat(dataFile); err != nil || info.Size() != entry.Size {
+		return Entry{}, ErrNotExist
+	}
+
+	c.touch(id, actionFile, dataFile)
+	return entry, nil
+}
+
+// GetFile looks up id like Get, additionally returning the path to the
+// cached output file.
+func (c *Cache) GetFile(id ActionID) (file string, entry Entry, err error) {
+	entry, err = c.Get(id)
+	if err != nil {
+		return "", Entry{}, err
+	}
+	return c.fileName(entry.OutputID[:], "d"), entry, nil
+}
+
+// Put stores the content read from r under id, returning its output ID and
+// size. Put is safe for concurrent use, including concurrent calls for the
+// same id: the underlying content file is written to a temporary name and
+// atomically renamed into place, so readers never observe a partial file.
+func (c *Cache) Put(id ActionID, r io.Reader) (OutputID, int64, error) {
+	if c.disabled {
+		h := sha256.New()
+		n, err := io.Copy(h, r)
+		if err != nil {
+			return OutputID{}, 0, err
+		}
+		var out OutputID
+		copy(out[:], h.Sum(nil))
+		return out, n, nil
+	}
+
+	tmp, err := os.CreateTemp(c.dir, "put-*")
+	if err != nil {
+		return OutputID{}, 0, err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // no-op once renamed
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, h), r)
+	closeErr := tmp.Close()
+	if err != nil {
+		return OutputID{}, 0, err
+	}
+	if closeErr != nil {
+		return OutputID{}, 0, closeErr
+	}
+
+	var out OutputID
+	copy(out[:], h.Sum(nil))
+
+	dataFile := c.fileName(out[:], "d")
+	// Refresh an existing data file's mtime so a concurrent Trim sees it
+	// as recently used; if it doesn't exist, move the new content into place.
+	if now := time.Now(); os.Chtimes(dataFile, now, now) != nil {
+		if err := os.Rename(tmpName, dataFile); err != nil {
+			return OutputID{}, 0, err
+		}
+	}
+
+	entry := Entry{OutputID: out, Size: n, Time: time.Now()}
+	if err := c.writeEntry(id, entry); err != nil {
+		return OutputID{}, 0, err
+	}
+	return out, n, nil
+}
+
+// touch refreshes the mtime of an entry's action and data files, at most
+// once per touchWindow, so that Trim's LRU-style eviction reflects recent
+// reads without rewriting files on every Get.
+func (c *Cache) touch(id ActionID, actionFile, dataFile string) {
+	now := time.Now()
+
+	c.mu.Lock()
+	last, ok := c.touched[id]
+	if ok && now.Sub(last) < touchWindow {
+		c.mu.Unlock()
+		return
+	}
+	c.touched[id] = now
+	c.mu.Unlock()
+
+	os.Chtimes(actionFile, now, now)
+	os.Chtimes(dataFile, now, now)
+}
+
+// writeEntry writes the action index file recording entry for id, holding
+// an exclusive lock for the duration of the write so that concurrent
+// readers (via lockedfile.Read, as Get uses) never observe a torn write,
+// even across processes sharing this cache directory.
+func (c *Cache) writeEntry(id ActionID, entry Entry) error {
+	actionFile := c.fileName(id[:], "a")
+	return lockedfile.Write(actionFile, formatEntry(entry), 0o644)
+}
+
+// Trim removes entries that have not been touched within the cache's TTL.
+// An output file is only removed once no live entry still references it,
+// so content shared by multiple actions survives as long as any one of
+// them is live.
+func (c *Cache) Trim() error {
+	if c.disabled {
+		return nil
+	}
+	cutoff := time.Now().Add(-c.ttl)
+
+	var expired []string
+	live := make(map[OutputID]bool)
+
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, "-a") {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if !info.ModTime().Before(cutoff) {
+			if data, err := lockedfile.Read(path); err == nil {
+				if entry, err := parseEntry(data); err == nil {
+					live[entry.OutputID] = true
+				}
+			}
+			return nil
+		}
+		expired = append(expired, path)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var deletedOutputs []OutputID
+	for _, actionFile := range expired {
+		out, deleted, err := c.evictIfStillExpired(actionFile, cutoff, live)
+		if err != nil {
+			return err
+		}
+		if deleted {
+			deletedOutputs = append(deletedOutputs, out)
+		}
+	}
+
+	for _, out := range deletedOutputs {
+		if out == (OutputID{}) || live[out] {
+			continue
+		}
+		// live was built from a snapshot taken before (and during) the
+		// eviction loop above, so it can't see a concurrent Put that
+		// (re)creates an action entry for this output after its own
+		// action file was evicted. Re-checking the data file's own
+		// mtime closes that gap: Put and touch both refresh it, so a
+		// data file some other entry still points to, or was just
+		// written, survives even though live missed it.
+		dataFile := c.fileName(out[:], "d")
+		if info, err := os.Stat(dataFile); err == nil && info.ModTime().Before(cutoff) {
+			lockedfile.Remove(dataFile)
+		}
+	}
+	return nil
+}
+
+// evictIfStillExpired re-validates and, if still warranted, deletes a
+// single action file. The whole read-decide-delete sequence runs while
+// holding the same exclusive lock writeEntry takes, so a concurrent Put
+// for this action id can't land in the gap between the first-pass scan
+// and the delete: it either completes entirely before this call starts,
+// in which case the refreshed mtime here causes eviction to be skipped, or
+// it blocks until this call (and its lock) is done.
+//
+// It returns the entry's output ID and whether the action file was
+// actually removed. live is updated in place with the output ID of any
+// entry found to no longer be expired.
+func (c *Cache) evictIfStillExpired(actionFile string, cutoff time.Time, live map[OutputID]bool) (OutputID, bool, error) {
+	f, err := lockedfile.OpenFile(actionFile, os.O_RDWR, 0)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return OutputID{}, false, nil
+		}
+		return OutputID{}, false, err
+	}
+	defer f.Close()
+
+	info, statErr := f.Stat()
+	data, readErr := io.ReadAll(f.File)
+	var entry Entry
+	if readErr == nil {
+		entry, _ = parseEntry(data) // zero Entry on parse failure; still evicted below
+
+		if statErr == nil && !info.ModTime().Before(cutoff) {
+			live[entry.OutputID] = true
+			return entry.OutputID, false, nil
+		}
+	}
+
+	if err := os.Remove(actionFile); err != nil && !os.IsNotExist(err) {
+		return OutputID{}, false, err
+	}
+	return entry.OutputID, true, nil
+}
+
+// fileName returns the sharded path for a 32-byte ID with the given kind
+// suffix ("a" for an action index, "d" for output data).
+func (c *Cache) fileName(id []byte, kind string) string {
+	hexID := hex.EncodeToString(id)
+	return filepath.Join(c.dir, hexID[:2], hexID+"-"+kind)
+}
+
+// formatEntry serializes entry in the on-disk action index format:
+// "v1 <output id hex> <size> <unix nanoseconds>\n".
+func formatEntry(entry Entry) []byte {
+	return []byte(fmt.Sprintf("v1 %x %d %d\n", entry.OutputID, entry.Size, entry.Time.UnixNano()))
+}
+
+// parseEntry parses the on-disk action index format written by formatEntry.
+func parseEntry(data []byte) (Entry, error) {
+	fields := strings.Fields(string(bytes.TrimSpace(data)))
+	if len(fields) != 4 || fields[0] != "v1" {
+		return Entry{}, fmt.Errorf("cache: malformed entry")
+	}
+	outBytes, err := hex.DecodeString(fields[1])
+	if err != nil || len(outBytes) != idSize {
+		return Entry{}, fmt.Errorf("cache: malformed output id")
+	}
+	size, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return Entry{}, fmt.Errorf("cache: malformed size")
+	}
+	nanos, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil {
+		return Entry{}, fmt.Errorf("cache: malformed time")
+	}
+	var entry Entry
+	copy(entry.OutputID[:], outBytes)
+	entry.Size = size
+	entry.Time = time.Unix(0, nanos)
+	return entry, nil
+}
@@ -0,0 +1,259 @@
+package cache
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testAction(b byte) ActionID {
+	var id ActionID
+	id[0] = b
+	return id
+}
+
+func TestPutGetRoundTrip(t *testing.T) {
+	c, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	id := testAction(1)
+	want := []byte("hello, cache")
+	outID, size, err := c.Put(id, bytes.NewReader(want))
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if size != int64(len(want)) {
+		t.Errorf("Put() size = %d, want %d", size, len(want))
+	}
+
+	entry, err := c.Get(id)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if entry.OutputID != outID {
+		t.Errorf("Get() OutputID = %x, want %x", entry.OutputID, outID)
+	}
+
+	file, _, err := c.GetFile(id)
+	if err != nil {
+		t.Fatalf("GetFile() error = %v", err)
+	}
+	got, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("ReadFile(%q) error = %v", file, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("GetFile() content = %q, want %q", got, want)
+	}
+}
+
+func TestGetMissReturnsErrNotExist(t *testing.T) {
+	c, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if _, err := c.Get(testAction(9)); err != ErrNotExist {
+		t.Errorf("Get() error = %v, want ErrNotExist", err)
+	}
+}
+
+func TestConcurrentPutGet(t *testing.T) {
+	c, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id := testAction(byte(i % 8)) // force collisions across goroutines
+			payload := bytes.Repeat([]byte{byte(i)}, 100)
+			if _, _, err := c.Put(id, bytes.NewReader(payload)); err != nil {
+				t.Errorf("Put() error = %v", err)
+				return
+			}
+			if _, err := c.Get(id); err != nil {
+				t.Errorf("Get() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestGetRecoversFromCorruptEntry(t *testing.T) {
+	dir := t.TempDir()
+	c, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	id := testAction(2)
+	if _, _, err := c.Put(id, strings.NewReader("data")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	if err := os.WriteFile(c.fileName(id[:], "a"), []byte("not a valid entry"), 0o644); err != nil {
+		t.Fatalf("corrupting entry: %v", err)
+	}
+
+	if _, err := c.Get(id); err != ErrNotExist {
+		t.Errorf("Get() after corruption = %v, want ErrNotExist", err)
+	}
+}
+
+func TestTrimEvictsExpiredEntries(t *testing.T) {
+	c, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	c.SetTTL(time.Hour)
+
+	oldID, freshID := testAction(3), testAction(4)
+	if _, _, err := c.Put(oldID, strings.NewReader("old")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if _, _, err := c.Put(freshID, strings.NewReader("fresh")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	old := time.Now().Add(-2 * time.Hour)
+	oldAction := c.fileName(oldID[:], "a")
+	if err := os.Chtimes(oldAction, old, old); err != nil {
+		t.Fatalf("Chtimes() error = %v", err)
+	}
+
+	if err := c.Trim(); err != nil {
+		t.Fatalf("Trim() error = %v", err)
+	}
+
+	if _, err := c.Get(oldID); err != ErrNotExist {
+		t.Errorf("Get(oldID) after Trim = %v, want ErrNotExist", err)
+	}
+	if _, err := c.Get(freshID); err != nil {
+		t.Errorf("Get(freshID) after Trim = %v, want nil", err)
+	}
+}
+
+func TestTrimKeepsSharedOutputAlive(t *testing.T) {
+	c, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	c.SetTTL(time.Hour)
+
+	oldID, freshID := testAction(5), testAction(6)
+	payload := "shared content"
+	if _, _, err := c.Put(oldID, strings.NewReader(payload)); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if _, _, err := c.Put(freshID, strings.NewReader(payload)); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(c.fileName(oldID[:], "a"), old, old); err != nil {
+		t.Fatalf("Chtimes() error = %v", err)
+	}
+
+	if err := c.Trim(); err != nil {
+		t.Fatalf("Trim() error = %v", err)
+	}
+
+	file, _, err := c.GetFile(freshID)
+	if err != nil {
+		t.Fatalf("GetFile(freshID) after Trim = %v", err)
+	}
+	if _, err := os.Stat(file); err != nil {
+		t.Errorf("output file removed even though freshID still references it: %v", err)
+	}
+}
+
+// TestTrimDoesNotClobberConcurrentPut guards against a Trim that decides an
+// entry is expired from a stale snapshot and then deletes it even though a
+// concurrent Put has since refreshed it. It runs Trim and Put concurrently,
+// racing them many times under -race to shake out the window.
+func TestTrimDoesNotClobberConcurrentPut(t *testing.T) {
+	c, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	c.SetTTL(time.Hour)
+
+	id := testAction(8)
+	if _, _, err := c.Put(id, strings.NewReader("v1")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	for i := 0; i < 20; i++ {
+		old := time.Now().Add(-2 * time.Hour)
+		if err := os.Chtimes(c.fileName(id[:], "a"), old, old); err != nil {
+			t.Fatalf("Chtimes() error = %v", err)
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := c.Trim(); err != nil {
+				t.Errorf("Trim() error = %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, _, err := c.Put(id, strings.NewReader("v2")); err != nil {
+				t.Errorf("Put() error = %v", err)
+			}
+		}()
+		wg.Wait()
+
+		if _, err := c.Get(id); err != nil {
+			t.Fatalf("iteration %d: Get() after racing Trim/Put = %v, want entry to survive", i, err)
+		}
+	}
+}
+
+func TestDisabledCacheIsNoOp(t *testing.T) {
+	t.Setenv(cacheEnvVar, "off")
+	c := Default()
+
+	id := testAction(7)
+	outID, size, err := c.Put(id, strings.NewReader("ignored"))
+	if err != nil {
+		t.Fatalf("Put() on disabled cache error = %v", err)
+	}
+	if size != int64(len("ignored")) {
+		t.Errorf("Put() size = %d, want %d", size, len("ignored"))
+	}
+	if outID == (OutputID{}) {
+		t.Errorf("Put() returned zero OutputID")
+	}
+
+	if _, err := c.Get(id); err != ErrNotExist {
+		t.Errorf("Get() on disabled cache = %v, want ErrNotExist", err)
+	}
+	if err := c.Trim(); err != nil {
+		t.Errorf("Trim() on disabled cache error = %v", err)
+	}
+}
+
+func TestOpenCreatesShardDirectories(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Open(dir); err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "00")); err != nil || !info.IsDir() {
+		t.Errorf("Open() did not create shard directory 00")
+	}
+	if info, err := os.Stat(filepath.Join(dir, "ff")); err != nil || !info.IsDir() {
+		t.Errorf("Open() did not create shard directory ff")
+	}
+}
@@ -0,0 +1,46 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// cacheEnvVar overrides the cache root resolved by Default, mirroring the
+// Go toolchain's GOCACHE. Setting it to "off" disables caching: Default
+// returns a Cache whose methods are no-ops.
+const cacheEnvVar = "UTILSCACHE"
+
+// disabledCache returns a no-op Cache, used whenever Default can't (or has
+// been told not to) set up a real on-disk cache.
+func disabledCache() *Cache {
+	return &Cache{disabled: true, touched: make(map[ActionID]time.Time)}
+}
+
+// Default opens a cache rooted under the user's cache directory (see
+// os.UserCacheDir); each call opens a new Cache. The root is overridable
+// via the UTILSCACHE
+// environment variable. Setting UTILSCACHE=off, or any failure to create
+// the cache directory, yields a disabled Cache: its methods succeed but
+// never read or write state on disk.
+func Default() *Cache {
+	if v := os.Getenv(cacheEnvVar); v != "" {
+		if v == "off" {
+			return disabledCache()
+		}
+		if c, err := Open(v); err == nil {
+			return c
+		}
+		return disabledCache()
+	}
+
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return disabledCache()
+	}
+	c, err := Open(filepath.Join(dir, "utils"))
+	if err != nil {
+		return disabledCache()
+	}
+	return c
+}
@@ -0,0 +1,162 @@
+// Package lockedfile provides advisory file locking so that multiple
+// processes can safely share a directory such as the one returned by
+// paths.Dirs.CacheDir, modeled on the approach used internally by the Go
+// toolchain (cmd/go/internal/lockedfile).
+//
+// Locking is advisory: it only excludes other lockedfile users, not
+// processes that open the file through other means. On platforms and
+// filesystems where neither flock(2) nor LockFileEx is available (some NFS
+// mounts, for example), a portable fallback using an O_EXCL sidecar lock
+// file is used instead.
+package lockedfile
+
+import (
+	"io"
+	"os"
+)
+
+// File is an *os.File that also holds an advisory lock. Close releases the
+// lock before closing the underlying file.
+type File struct {
+	*os.File
+	unlock func() error
+}
+
+// Close unlocks and closes the file.
+func (f *File) Close() error {
+	unlockErr := f.unlock()
+	closeErr := f.File.Close()
+	if unlockErr != nil {
+		return unlockErr
+	}
+	return closeErr
+}
+
+// OpenFile is like os.OpenFile, but the returned File also holds an
+// advisory lock: exclusive if flag includes os.O_WRONLY or os.O_RDWR,
+// shared otherwise. The lock is held until the File is closed, and blocks
+// until any conflicting lock held by another process is released.
+//
+// If flag includes os.O_TRUNC, the truncation is deferred until after the
+// lock is acquired. The os.O_TRUNC semantics of the underlying open(2)
+// syscall apply immediately, before any lock is taken, which would
+// otherwise let one caller's open silently clear a file while another
+// caller is mid-read-modify-write under its own lock on the same path.
+//
+// open(2) itself is not synchronized by the lock either: a caller can open
+// name, then block waiting for the lock while a concurrent Remove deletes
+// name and releases its own lock, at which point the blocked caller's
+// flock succeeds on a file descriptor that now refers to a detached,
+// unlinked inode — any write through it would be silently lost. Once the
+// lock is held, OpenFile guards against this by confirming (via
+// os.SameFile) that the descriptor still refers to the current directory
+// entry for name, reopening from scratch if not.
+func OpenFile(name string, flag int, perm os.FileMode) (*File, error) {
+	truncate := flag&os.O_TRUNC != 0
+	openFlag := flag &^ os.O_TRUNC
+	exclusive := flag&(os.O_WRONLY|os.O_RDWR) != 0
+
+	for {
+		f, err := os.OpenFile(name, openFlag, perm)
+		if err != nil {
+			return nil, err
+		}
+
+		unlock, err := lock(f, exclusive)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+
+		opened, fErr := f.Stat()
+		current, statErr := os.Stat(name)
+		if fErr != nil || statErr != nil || !os.SameFile(opened, current) {
+			// name was removed or replaced while we waited for the lock;
+			// this descriptor is stale, so start over.
+			unlock()
+			f.Close()
+			continue
+		}
+
+		if truncate {
+			if err := f.Truncate(0); err != nil {
+				unlock()
+				f.Close()
+				return nil, err
+			}
+		}
+		return &File{File: f, unlock: unlock}, nil
+	}
+}
+
+// Open opens name for reading under a shared lock, as os.Open would.
+func Open(name string) (*File, error) {
+	return OpenFile(name, os.O_RDONLY, 0)
+}
+
+// Create opens name for reading and writing under an exclusive lock,
+// creating and truncating it as needed, as os.Create would.
+func Create(name string) (*File, error) {
+	return OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o666)
+}
+
+// Edit opens name for reading and writing under an exclusive lock, without
+// truncating it, creating it if necessary, and invokes fn with the open
+// file. The lock is held for fn's entire duration, so fn can safely read
+// the current content, decide on an update, and write it back without
+// racing another process's Edit. The file is closed (and the lock
+// released) before Edit returns, even if fn returns an error.
+func Edit(name string, fn func(f *os.File) error) error {
+	f, err := OpenFile(name, os.O_RDWR|os.O_CREATE, 0o666)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return fn(f.File)
+}
+
+// Read opens name under a shared lock and returns its entire contents, as
+// os.ReadFile would.
+func Read(name string) ([]byte, error) {
+	f, err := Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f.File)
+}
+
+// Write opens name under an exclusive lock and writes data to it,
+// creating or truncating it as needed, as os.WriteFile would.
+func Write(name string, data []byte, perm os.FileMode) error {
+	f, err := OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	closeErr := f.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// Remove takes out an exclusive lock on name and removes it, so that the
+// removal can't land in the middle of a concurrent Write or Edit (which
+// would otherwise risk detaching the file a writer still holds open from
+// its directory entry). It returns nil if name does not exist.
+func Remove(name string) error {
+	f, err := OpenFile(name, os.O_RDWR, 0)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	removeErr := os.Remove(name)
+	closeErr := f.Close()
+	if removeErr != nil && !os.IsNotExist(removeErr) {
+		return removeErr
+	}
+	return closeErr
+}
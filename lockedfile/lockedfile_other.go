@@ -0,0 +1,11 @@
+//go:build !unix && !windows
+
+package lockedfile
+
+import "os"
+
+// lock emulates an advisory lock on f using an O_EXCL sidecar file, since
+// this platform has no native file-range locking primitive wired up here.
+func lock(f *os.File, exclusive bool) (func() error, error) {
+	return lockPortable(f, exclusive)
+}
@@ -0,0 +1,40 @@
+package lockedfile
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// lockSuffix names the sidecar lock file used when no native file-range
+// locking primitive is available: on platforms with neither flock(2) nor
+// LockFileEx wired up (see lockedfile_other.go), and as a fallback on
+// unix filesystems where flock(2) itself isn't supported, such as some
+// NFS mounts (see lockedfile_unix.go).
+const lockSuffix = ".lock"
+
+// lockPollInterval is how often we retry acquiring the sidecar lock file
+// while it's held by someone else.
+const lockPollInterval = 50 * time.Millisecond
+
+// lockPortable emulates an advisory lock on f using an O_EXCL sidecar
+// file. It does not distinguish shared from exclusive locks: any lock
+// excludes any other.
+func lockPortable(f *os.File, exclusive bool) (func() error, error) {
+	lockPath := f.Name() + lockSuffix
+	for {
+		lf, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o666)
+		if err == nil {
+			lf.Close()
+			break
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("lockedfile: creating lock file %q: %w", lockPath, err)
+		}
+		time.Sleep(lockPollInterval)
+	}
+
+	return func() error {
+		return os.Remove(lockPath)
+	}, nil
+}
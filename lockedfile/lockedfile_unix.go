@@ -0,0 +1,43 @@
+//go:build unix
+
+package lockedfile
+
+import (
+	"os"
+	"syscall"
+)
+
+// lock takes an advisory flock(2) lock on f, exclusive or shared, blocking
+// until it is available. The returned function releases the lock.
+//
+// Some filesystems, notably certain NFS mounts, don't implement flock(2)
+// and return ENOTSUP/EOPNOTSUPP; in that case lock falls back to the
+// portable O_EXCL sidecar-file implementation instead of failing outright.
+func lock(f *os.File, exclusive bool) (func() error, error) {
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+
+	fd := int(f.Fd())
+	for {
+		err := syscall.Flock(fd, how)
+		if err == nil {
+			break
+		}
+		if err == syscall.EINTR {
+			continue
+		}
+		if err == syscall.ENOTSUP || err == syscall.EOPNOTSUPP {
+			return lockPortable(f, exclusive)
+		}
+		return nil, &os.PathError{Op: "flock", Path: f.Name(), Err: err}
+	}
+
+	return func() error {
+		if err := syscall.Flock(fd, syscall.LOCK_UN); err != nil {
+			return &os.PathError{Op: "funlock", Path: f.Name(), Err: err}
+		}
+		return nil
+	}, nil
+}
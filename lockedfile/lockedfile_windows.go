@@ -0,0 +1,35 @@
+//go:build windows
+
+package lockedfile
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lock takes an advisory LockFileEx lock on f, exclusive or shared,
+// blocking until it is available. The returned function releases the lock.
+func lock(f *os.File, exclusive bool) (func() error, error) {
+	var flags uint32
+	if exclusive {
+		flags |= windows.LOCKFILE_EXCLUSIVE_LOCK
+	}
+
+	handle := windows.Handle(f.Fd())
+	// Lock an arbitrary (but fixed) single byte range; readers and writers
+	// only ever use this one range to coordinate, so it behaves like a
+	// whole-file lock without needing to know the file's size up front.
+	ol := new(windows.Overlapped)
+	if err := windows.LockFileEx(handle, flags, 0, 1, 0, ol); err != nil {
+		return nil, &os.PathError{Op: "lockfileex", Path: f.Name(), Err: err}
+	}
+
+	return func() error {
+		ol := new(windows.Overlapped)
+		if err := windows.UnlockFileEx(handle, 0, 1, 0, ol); err != nil {
+			return &os.PathError{Op: "unlockfileex", Path: f.Name(), Err: err}
+		}
+		return nil
+	}, nil
+}
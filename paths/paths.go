@@ -0,0 +1,184 @@
+// Package paths resolves per-application cache, config, data, state, and
+// runtime directories following the XDG Base Directory spec on Linux, with
+// conventional fallbacks on macOS and Windows.
+package paths
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Dirs resolves standard directories for a single application. Each
+// directory is computed and created lazily on first access, then cached
+// for the lifetime of the value.
+//
+// Construct one with New.
+type Dirs struct {
+	appName string
+
+	cache   lazyDir
+	config  lazyDir
+	data    lazyDir
+	state   lazyDir
+	runtime lazyDir
+}
+
+// lazyDir memoizes the resolution (and MkdirAll) of a single directory.
+type lazyDir struct {
+	once sync.Once
+	path string
+	err  error
+}
+
+func (l *lazyDir) resolve(compute func() (string, error)) (string, error) {
+	l.once.Do(func() {
+		l.path, l.err = compute()
+		if l.err == nil {
+			l.err = os.MkdirAll(l.path, 0o755)
+		}
+	})
+	return l.path, l.err
+}
+
+// New returns a Dirs for the given application name. The name is used both
+// to namespace each resolved directory (e.g. joined onto the OS cache root)
+// and to derive app-specific override environment variables, such as
+// MYAPP_CACHE_DIR for appName "myapp".
+func New(appName string) *Dirs {
+	return &Dirs{appName: appName}
+}
+
+// CacheDir returns the application's cache directory, creating it if
+// necessary. Non-essential, regenerable data (thumbnails, build artifacts,
+// downloaded indexes) belongs here.
+func (d *Dirs) CacheDir() (string, error) {
+	return d.cache.resolve(func() (string, error) {
+		return d.namespaced("CACHE_DIR", os.UserCacheDir)
+	})
+}
+
+// ConfigDir returns the application's configuration directory, creating it
+// if necessary.
+func (d *Dirs) ConfigDir() (string, error) {
+	return d.config.resolve(func() (string, error) {
+		return d.namespaced("CONFIG_DIR", os.UserConfigDir)
+	})
+}
+
+// DataDir returns the application's data directory, creating it if
+// necessary. Use this for durable application data that should survive a
+// cache wipe.
+func (d *Dirs) DataDir() (string, error) {
+	return d.data.resolve(func() (string, error) {
+		return d.namespaced("DATA_DIR", dataRoot)
+	})
+}
+
+// StateDir returns the application's state directory, creating it if
+// necessary. Use this for data that should persist across restarts but is
+// less critical than DataDir, such as logs, history, or undo state.
+func (d *Dirs) StateDir() (string, error) {
+	return d.state.resolve(func() (string, error) {
+		return d.namespaced("STATE_DIR", stateRoot)
+	})
+}
+
+// RuntimeDir returns the application's runtime directory, creating it if
+// necessary. This directory is meant for sockets, PID files, and other
+// ephemeral state tied to the current login session; callers should not
+// assume its contents survive a reboot.
+func (d *Dirs) RuntimeDir() (string, error) {
+	return d.runtime.resolve(func() (string, error) {
+		return d.namespaced("RUNTIME_DIR", runtimeRoot)
+	})
+}
+
+// namespaced resolves the root directory for a given kind and joins the
+// application name onto it. The app-specific override (e.g. MYAPP_CACHE_DIR)
+// takes precedence over whatever root itself would resolve, which is where
+// the XDG environment variable (or platform default) is consulted.
+func (d *Dirs) namespaced(appSuffix string, root func() (string, error)) (string, error) {
+	if v := os.Getenv(d.envName(appSuffix)); v != "" {
+		return filepath.Join(v, d.appName), nil
+	}
+	base, err := root()
+	if err != nil {
+		return "", fmt.Errorf("paths: resolving directory for %q: %w", d.appName, err)
+	}
+	return filepath.Join(base, d.appName), nil
+}
+
+// envName derives the app-specific override variable name for suffix
+// "CACHE_DIR", e.g. "MYAPP_CACHE_DIR" for appName "myapp" and "MY_APP_CACHE_DIR"
+// for appName "my-app".
+func (d *Dirs) envName(suffix string) string {
+	var b strings.Builder
+	for _, r := range d.appName {
+		switch {
+		case r >= 'a' && r <= 'z':
+			b.WriteRune(r - ('a' - 'A'))
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	b.WriteByte('_')
+	b.WriteString(suffix)
+	return b.String()
+}
+
+// dataRoot resolves the platform default data root. os.UserCacheDir and
+// os.UserConfigDir already handle XDG_CACHE_HOME and XDG_CONFIG_HOME
+// directly, but the standard library has no UserDataDir, so XDG_DATA_HOME
+// is handled here.
+func dataRoot() (string, error) {
+	if runtime.GOOS == "linux" {
+		if v := os.Getenv("XDG_DATA_HOME"); v != "" {
+			return v, nil
+		}
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, ".local", "share"), nil
+	}
+	// macOS and Windows have no dedicated data root distinct from config;
+	// os.UserConfigDir already returns Library/Application Support and
+	// %AppData% respectively.
+	return os.UserConfigDir()
+}
+
+// stateRoot resolves the platform default state root, via XDG_STATE_HOME on
+// Linux. Platforms without an XDG_STATE_HOME analogue fall back to the data
+// root.
+func stateRoot() (string, error) {
+	if runtime.GOOS == "linux" {
+		if v := os.Getenv("XDG_STATE_HOME"); v != "" {
+			return v, nil
+		}
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, ".local", "state"), nil
+	}
+	return dataRoot()
+}
+
+// runtimeRoot resolves the platform default runtime root, via
+// XDG_RUNTIME_DIR on Linux. Platforms without an XDG_RUNTIME_DIR analogue,
+// and Linux sessions that don't set it, fall back to the OS temp directory,
+// matching what most CLI tools do in its absence.
+func runtimeRoot() (string, error) {
+	if runtime.GOOS == "linux" {
+		if v := os.Getenv("XDG_RUNTIME_DIR"); v != "" {
+			return v, nil
+		}
+	}
+	return os.TempDir(), nil
+}
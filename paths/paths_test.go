@@ -0,0 +1,91 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCacheDirAppOverride(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("MYAPP_CACHE_DIR", root)
+
+	d := New("myapp")
+	got, err := d.CacheDir()
+	if err != nil {
+		t.Fatalf("CacheDir() error = %v", err)
+	}
+
+	want := filepath.Join(root, "myapp")
+	if got != want {
+		t.Errorf("CacheDir() = %q, want %q", got, want)
+	}
+	if fi, err := os.Stat(got); err != nil || !fi.IsDir() {
+		t.Errorf("CacheDir() did not create %q", got)
+	}
+}
+
+func TestDataDirXDGOverride(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", root)
+
+	d := New("myapp")
+	got, err := d.DataDir()
+	if err != nil {
+		t.Fatalf("DataDir() error = %v", err)
+	}
+
+	want := filepath.Join(root, "myapp")
+	if got != want {
+		t.Errorf("DataDir() = %q, want %q", got, want)
+	}
+}
+
+func TestAppOverrideWinsOverXDG(t *testing.T) {
+	xdgRoot := t.TempDir()
+	appRoot := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdgRoot)
+	t.Setenv("MYAPP_CONFIG_DIR", appRoot)
+
+	d := New("myapp")
+	got, err := d.ConfigDir()
+	if err != nil {
+		t.Fatalf("ConfigDir() error = %v", err)
+	}
+
+	want := filepath.Join(appRoot, "myapp")
+	if got != want {
+		t.Errorf("ConfigDir() = %q, want %q (app-specific override should win)", got, want)
+	}
+}
+
+func TestEnvNameSanitizesAppName(t *testing.T) {
+	d := New("my-cool.app")
+	got := d.envName("CACHE_DIR")
+	want := "MY_COOL_APP_CACHE_DIR"
+	if got != want {
+		t.Errorf("envName() = %q, want %q", got, want)
+	}
+}
+
+func TestDirsAreMemoized(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("MYAPP_STATE_DIR", root)
+
+	d := New("myapp")
+	first, err := d.StateDir()
+	if err != nil {
+		t.Fatalf("StateDir() error = %v", err)
+	}
+
+	// Changing the env var after the first resolution must not affect the
+	// cached value.
+	t.Setenv("MYAPP_STATE_DIR", t.TempDir())
+	second, err := d.StateDir()
+	if err != nil {
+		t.Fatalf("StateDir() error = %v", err)
+	}
+	if first != second {
+		t.Errorf("StateDir() changed after first call: %q then %q", first, second)
+	}
+}
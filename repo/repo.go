@@ -0,0 +1,178 @@
+// Package repo treats a directory as a simple repository of byte-stream
+// objects keyed by a string ID, suitable for layering over either
+// paths.Dirs.DataDir (durable storage) or paths.Dirs.CacheDir (ephemeral
+// storage).
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Repository stores objects as files under a root directory. IDs map to
+// paths by splitting on "/", so "images/avatar.png" is stored as
+// root/images/avatar.png; intermediate directories are created on demand.
+type Repository struct {
+	root     string
+	filePerm os.FileMode
+	dirPerm  os.FileMode
+}
+
+// New returns a Repository rooted at root, creating it if necessary.
+// filePerm and dirPerm are used for objects and directories created by
+// subsequent calls.
+func New(root string, filePerm, dirPerm os.FileMode) (*Repository, error) {
+	if err := os.MkdirAll(root, dirPerm); err != nil {
+		return nil, fmt.Errorf("repo: opening %q: %w", root, err)
+	}
+	return &Repository{root: root, filePerm: filePerm, dirPerm: dirPerm}, nil
+}
+
+// Put writes the contents of r to id, creating or truncating it, and
+// creating any intermediate directories implied by id. The write is
+// atomic: readers never observe a partially written object.
+func (r *Repository) Put(id string, src io.Reader) error {
+	path, err := r.path(id)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), r.dirPerm); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".repo-tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // no-op once renamed
+
+	_, copyErr := io.Copy(tmp, src)
+	closeErr := tmp.Close()
+	if copyErr != nil {
+		return copyErr
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+	if err := os.Chmod(tmpName, r.filePerm); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, path)
+}
+
+// Get opens id for reading. The caller must Close the returned
+// io.ReadCloser.
+func (r *Repository) Get(id string) (io.ReadCloser, error) {
+	path, err := r.path(id)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// Exists reports whether id is present in the repository.
+func (r *Repository) Exists(id string) bool {
+	path, err := r.path(id)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// Stat returns file info for id, as os.Stat would.
+func (r *Repository) Stat(id string) (fs.FileInfo, error) {
+	path, err := r.path(id)
+	if err != nil {
+		return nil, err
+	}
+	return os.Stat(path)
+}
+
+// Delete removes id from the repository. It returns nil if id does not
+// exist.
+func (r *Repository) Delete(id string) error {
+	path, err := r.path(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// List returns the IDs of every object located under the directory named
+// by prefix, matched by whole path component rather than raw string
+// prefix: List("a") returns "a/1" but not "ab/1". Results are sorted by
+// filepath.WalkDir's usual lexical directory order. An empty prefix lists
+// every object in the repository. List returns an empty slice, not an
+// error, if prefix names a directory that doesn't exist.
+func (r *Repository) List(prefix string) ([]string, error) {
+	base := r.root
+	if prefix != "" {
+		p, err := r.path(prefix)
+		if err != nil {
+			return nil, err
+		}
+		base = p
+	}
+
+	var ids []string
+	err := filepath.WalkDir(base, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == base {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(r.root, path)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, filepath.ToSlash(rel))
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return ids, err
+}
+
+// path maps id to its on-disk location, rejecting IDs that would escape
+// root via "." or ".." path segments. IDs are split on "/" only, the one
+// separator this package recognizes; a literal "\" is rejected outright
+// rather than treated as a separator, since filepath.Join would otherwise
+// interpret it as one on Windows and let a single opaque-looking segment
+// (e.g. "..\\..\\Windows\\System32") walk out of root.
+func (r *Repository) path(id string) (string, error) {
+	if strings.ContainsRune(id, '\\') {
+		return "", fmt.Errorf("repo: invalid id %q: contains %q", id, `\`)
+	}
+
+	segments := strings.Split(id, "/")
+	clean := make([]string, 0, len(segments))
+	for _, seg := range segments {
+		switch seg {
+		case "":
+			continue
+		case ".", "..":
+			return "", fmt.Errorf("repo: invalid id %q: contains %q", id, seg)
+		default:
+			clean = append(clean, seg)
+		}
+	}
+	if len(clean) == 0 {
+		return "", fmt.Errorf("repo: invalid id %q: empty", id)
+	}
+	return filepath.Join(append([]string{r.root}, clean...)...), nil
+}
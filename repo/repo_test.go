@@ -0,0 +1,155 @@
+package repo
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestPutGetRoundTrip(t *testing.T) {
+	r, err := New(t.TempDir(), 0o644, 0o755)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if err := r.Put("images/avatar.png", strings.NewReader("pixels")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	rc, err := r.Get("images/avatar.png")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer rc.Close()
+
+	got, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if string(got) != "pixels" {
+		t.Errorf("Get() content = %q, want %q", got, "pixels")
+	}
+}
+
+func TestExistsAndDelete(t *testing.T) {
+	r, err := New(t.TempDir(), 0o644, 0o755)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if r.Exists("missing") {
+		t.Errorf("Exists() = true for object never Put")
+	}
+
+	if err := r.Put("present", strings.NewReader("x")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if !r.Exists("present") {
+		t.Errorf("Exists() = false after Put")
+	}
+
+	if err := r.Delete("present"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if r.Exists("present") {
+		t.Errorf("Exists() = true after Delete")
+	}
+
+	// Deleting an already-absent ID is not an error.
+	if err := r.Delete("present"); err != nil {
+		t.Errorf("Delete() on missing id error = %v, want nil", err)
+	}
+}
+
+func TestListByPrefix(t *testing.T) {
+	r, err := New(t.TempDir(), 0o644, 0o755)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	for _, id := range []string{"a/1", "a/2", "b/1"} {
+		if err := r.Put(id, strings.NewReader(id)); err != nil {
+			t.Fatalf("Put(%q) error = %v", id, err)
+		}
+	}
+
+	got, err := r.List("a")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	sort.Strings(got)
+	want := []string{"a/1", "a/2"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("List(%q) = %v, want %v", "a", got, want)
+	}
+
+	all, err := r.List("")
+	if err != nil {
+		t.Fatalf("List(\"\") error = %v", err)
+	}
+	if len(all) != 3 {
+		t.Errorf("List(\"\") returned %d ids, want 3", len(all))
+	}
+}
+
+func TestListMissingPrefixReturnsEmpty(t *testing.T) {
+	r, err := New(t.TempDir(), 0o644, 0o755)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	got, err := r.List("nonexistent")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("List() = %v, want empty", got)
+	}
+}
+
+func TestPathRejectsTraversal(t *testing.T) {
+	r, err := New(t.TempDir(), 0o644, 0o755)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	for _, id := range []string{"../escape", "a/../../escape", "..", `..\..\..\Windows\System32\evil`} {
+		if err := r.Put(id, strings.NewReader("x")); err == nil {
+			t.Errorf("Put(%q) succeeded, want error", id)
+		}
+	}
+}
+
+func TestStatReportsFilePerm(t *testing.T) {
+	r, err := New(t.TempDir(), 0o600, 0o755)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := r.Put("obj", strings.NewReader("data")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	info, err := r.Stat("obj")
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("Stat() mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestNestedDirectoriesCreatedOnDemand(t *testing.T) {
+	root := t.TempDir()
+	r, err := New(root, 0o644, 0o755)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := r.Put("deep/nested/path/obj", strings.NewReader("x")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "deep", "nested", "path", "obj")); err != nil {
+		t.Errorf("expected nested file on disk: %v", err)
+	}
+}
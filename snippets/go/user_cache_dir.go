@@ -2,14 +2,37 @@ package main
 
 import (
 	"fmt"
-	"os"
+
+	"github.com/albertocavalcante/utils/paths"
 )
 
 func main() {
-	userCacheDir, err := os.UserCacheDir()
+	dirs := paths.New("myapp")
+
+	cacheDir, err := dirs.CacheDir()
+	if err != nil {
+		panic(err)
+	}
+	configDir, err := dirs.ConfigDir()
+	if err != nil {
+		panic(err)
+	}
+	dataDir, err := dirs.DataDir()
+	if err != nil {
+		panic(err)
+	}
+	stateDir, err := dirs.StateDir()
+	if err != nil {
+		panic(err)
+	}
+	runtimeDir, err := dirs.RuntimeDir()
 	if err != nil {
 		panic(err)
 	}
 
-	fmt.Printf("User cache dir: %s\n", userCacheDir)
+	fmt.Printf("Cache dir:   %s\n", cacheDir)
+	fmt.Printf("Config dir:  %s\n", configDir)
+	fmt.Printf("Data dir:    %s\n", dataDir)
+	fmt.Printf("State dir:   %s\n", stateDir)
+	fmt.Printf("Runtime dir: %s\n", runtimeDir)
 }